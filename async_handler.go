@@ -0,0 +1,211 @@
+// Copyright 2016 Jim Zhang (jim.zoumo@gmail.com)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package logdog
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/zoumo/logdog/pkg/pythonic"
+)
+
+// overflowPolicy decides what AsyncHandler does when its buffer is full
+type overflowPolicy string
+
+const (
+	overflowBlock      overflowPolicy = "block"
+	overflowDropNewest overflowPolicy = "drop_newest"
+	overflowDropOldest overflowPolicy = "drop_oldest"
+)
+
+// AsyncHandler wraps another Handler and decouples Handle from the
+// caller's goroutine. Records are pushed onto a bounded channel and
+// drained by one or more background workers, so the wrapped handler's
+// formatting and I/O no longer serialize every logging goroutine.
+type AsyncHandler struct {
+	Target         Handler
+	BufferSize     int
+	Workers        int
+	OverflowPolicy overflowPolicy
+	FlushOnClose   bool
+
+	Name  string
+	Level int
+
+	queue  chan *LogRecord
+	wg     sync.WaitGroup
+	mu     sync.RWMutex
+	closed bool
+	ConfigLoader
+}
+
+// NewAsyncHandler returns a new AsyncHandler fully initialized
+func NewAsyncHandler() *AsyncHandler {
+	return &AsyncHandler{
+		Name:           "",
+		Level:          NOTHING,
+		BufferSize:     1024,
+		Workers:        1,
+		OverflowPolicy: overflowBlock,
+		FlushOnClose:   true,
+	}
+}
+
+// LoadConfig loads config from its input and
+// stores it in the value pointed to by c
+func (hdlr *AsyncHandler) LoadConfig(c map[string]interface{}) error {
+	config, err := pythonic.DictReflect(c)
+	if err != nil {
+		return err
+	}
+
+	hdlr.Name = config.MustGetString("name", "")
+	hdlr.Level = GetLevelByName(config.MustGetString("level", "NOTHING"))
+
+	target := config.MustGetString("target", "")
+	if target == "" {
+		return fmt.Errorf("Should provide a target handler name")
+	}
+	wrapped := GetHandler(target)
+	if wrapped == nil {
+		return fmt.Errorf("can not find handler: %s", target)
+	}
+	hdlr.Target = wrapped
+
+	hdlr.BufferSize = config.MustGetInt("bufferSize", 1024)
+	hdlr.Workers = config.MustGetInt("workers", 1)
+	hdlr.FlushOnClose = config.MustGetBool("flushOnClose", true)
+
+	policy := overflowPolicy(config.MustGetString("overflowPolicy", "block"))
+	switch policy {
+	case overflowBlock, overflowDropNewest, overflowDropOldest:
+		hdlr.OverflowPolicy = policy
+	default:
+		return fmt.Errorf("unknown overflowPolicy: %s", policy)
+	}
+
+	hdlr.start()
+
+	return nil
+}
+
+// start spins up the background workers that drain the buffer into Target
+func (hdlr *AsyncHandler) start() {
+	hdlr.queue = make(chan *LogRecord, hdlr.BufferSize)
+	workers := hdlr.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		hdlr.wg.Add(1)
+		go hdlr.worker()
+	}
+}
+
+func (hdlr *AsyncHandler) worker() {
+	defer hdlr.wg.Done()
+	for record := range hdlr.queue {
+		hdlr.Target.Handle(record)
+	}
+}
+
+// Filter check if handler should filter the specified record
+func (hdlr *AsyncHandler) Filter(record *LogRecord) bool {
+	if record.Level < hdlr.Level {
+		return true
+	}
+	return false
+}
+
+// Handle the specified record, filter and hand it off to a worker
+// goroutine. The closed-check and the send onto queue happen under the
+// same RLock that Close takes exclusively before closing queue, so a
+// Handle call can never send on an already-closed channel: Close either
+// runs entirely before this RLock is acquired (closed is already true,
+// so Handle returns without touching queue) or waits for every in-flight
+// RLock holder - including one blocked mid-send - to finish first.
+func (hdlr *AsyncHandler) Handle(record *LogRecord) {
+	if hdlr.Filter(record) {
+		return
+	}
+
+	hdlr.mu.RLock()
+	defer hdlr.mu.RUnlock()
+	if hdlr.closed {
+		return
+	}
+
+	switch hdlr.OverflowPolicy {
+	case overflowDropNewest:
+		select {
+		case hdlr.queue <- record:
+		default:
+			// buffer full, drop the incoming record
+		}
+	case overflowDropOldest:
+		select {
+		case hdlr.queue <- record:
+		default:
+			select {
+			case <-hdlr.queue:
+			default:
+			}
+			select {
+			case hdlr.queue <- record:
+			default:
+			}
+		}
+	default: // overflowBlock
+		hdlr.queue <- record
+	}
+}
+
+// Emit formats and writes record via the wrapped Target handler
+func (hdlr *AsyncHandler) Emit(record *LogRecord) {
+	hdlr.Target.Emit(record)
+}
+
+// Close drains the buffer, stops the workers and closes the wrapped handler
+func (hdlr *AsyncHandler) Close() error {
+	hdlr.mu.Lock()
+	if hdlr.closed {
+		hdlr.mu.Unlock()
+		return nil
+	}
+	hdlr.closed = true
+	hdlr.mu.Unlock()
+
+	if !hdlr.FlushOnClose {
+		// drain whatever is left without waiting for it to be handled
+		for {
+			select {
+			case <-hdlr.queue:
+			default:
+				close(hdlr.queue)
+				hdlr.wg.Wait()
+				return hdlr.Target.Close()
+			}
+		}
+	}
+
+	close(hdlr.queue)
+	hdlr.wg.Wait()
+	return hdlr.Target.Close()
+}
+
+func init() {
+	RegisterConstructor("AsyncHandler", func() ConfigLoader {
+		return NewAsyncHandler()
+	})
+}
@@ -0,0 +1,56 @@
+// Copyright 2016 Jim Zhang (jim.zoumo@gmail.com)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package logdog
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// countingHandler counts how many records it was asked to handle
+type countingHandler struct {
+	count int64
+}
+
+func (h *countingHandler) Handle(record *LogRecord)      { atomic.AddInt64(&h.count, 1) }
+func (h *countingHandler) Filter(record *LogRecord) bool { return false }
+func (h *countingHandler) Emit(record *LogRecord)        {}
+func (h *countingHandler) Close() error                  { return nil }
+
+func TestAsyncHandlerHandleCloseRace(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		hdlr := NewAsyncHandler()
+		hdlr.Target = &countingHandler{}
+		hdlr.OverflowPolicy = overflowBlock
+		hdlr.start()
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				hdlr.Handle(&LogRecord{Level: INFO, Message: "race"})
+			}
+		}()
+
+		go func() {
+			defer wg.Done()
+			hdlr.Close()
+		}()
+
+		wg.Wait()
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2016 Jim Zhang (jim.zoumo@gmail.com)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package logdog
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"unsafe"
+)
+
+// fieldsNode links a Fields map to its parent, forming a cheap
+// copy-on-write chain so WithFields never has to flatten or copy an
+// ancestor's map just to attach one more.
+type fieldsNode struct {
+	parent *fieldsNode
+	own    Fields
+}
+
+// loggerFields tracks the fields chain attached to a child Logger
+// returned by WithFields/WithContext. A plain Logger from NewLogger has
+// no entry here; each of its log calls still takes loggerFieldsMu for
+// one map lookup that finds nothing.
+//
+// It is keyed by uintptr rather than *Logger on purpose: a map keyed by
+// the pointer itself would hold a strong reference to every child
+// Logger ever created via WithFields/WithContext, which never gets
+// collected (exactly the per-request-logger leak this API invites). The
+// uintptr key holds no reference, so the child is free to be collected
+// once its last caller drops it; a finalizer registered in WithFields
+// removes the now-stale entry when that happens.
+var (
+	loggerFieldsMu  sync.Mutex
+	loggerFieldsMap = make(map[uintptr]*fieldsNode)
+)
+
+// WithFields returns a child Logger that inherits fields on every
+// subsequent log call. Fields passed directly to Info/Warning/etc at the
+// call site are merged on top of - and win over - the inherited ones.
+// The receiver is left untouched.
+func (logger *Logger) WithFields(fields Fields) *Logger {
+	logger.mu.Lock()
+	child := &Logger{
+		Name:     logger.Name,
+		Level:    logger.Level,
+		Handlers: logger.Handlers,
+	}
+	logger.mu.Unlock()
+
+	var parent *fieldsNode
+	if node, ok := lookupFields(logger); ok {
+		parent = node
+	}
+	node := &fieldsNode{parent: parent, own: fields}
+
+	key := uintptr(unsafe.Pointer(child))
+	loggerFieldsMu.Lock()
+	loggerFieldsMap[key] = node
+	loggerFieldsMu.Unlock()
+
+	runtime.SetFinalizer(child, func(l *Logger) {
+		loggerFieldsMu.Lock()
+		delete(loggerFieldsMap, uintptr(unsafe.Pointer(l)))
+		loggerFieldsMu.Unlock()
+	})
+
+	return child
+}
+
+// lookupFields returns the fields chain attached to logger, if any.
+func lookupFields(logger *Logger) (*fieldsNode, bool) {
+	loggerFieldsMu.Lock()
+	defer loggerFieldsMu.Unlock()
+	node, ok := loggerFieldsMap[uintptr(unsafe.Pointer(logger))]
+	return node, ok
+}
+
+type fieldsContextKey struct{}
+
+// ContextWithFields returns a copy of ctx carrying fields, so code that
+// only has a context.Context can hand them to Logger.WithContext later.
+func ContextWithFields(ctx context.Context, fields Fields) context.Context {
+	return context.WithValue(ctx, fieldsContextKey{}, fields)
+}
+
+// WithContext returns a child Logger carrying any Fields previously
+// attached to ctx via ContextWithFields. If ctx carries none, it behaves
+// like WithFields(nil).
+func (logger *Logger) WithContext(ctx context.Context) *Logger {
+	fields, _ := ctx.Value(fieldsContextKey{}).(Fields)
+	return logger.WithFields(fields)
+}
+
+// inheritedFields flattens the fields chain attached to logger, if any,
+// into a single Fields map with the innermost (most specific) WithFields
+// call winning on key collisions. It returns nil for a plain Logger.
+func inheritedFields(logger *Logger) Fields {
+	node, ok := lookupFields(logger)
+	if !ok {
+		return nil
+	}
+
+	var chain []*fieldsNode
+	for n := node; n != nil; n = n.parent {
+		chain = append(chain, n)
+	}
+
+	merged := make(Fields, len(chain))
+	for i := len(chain) - 1; i >= 0; i-- {
+		for k, v := range chain[i].own {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+// mergeFields merges inherited context onto callSite, with callSite
+// taking precedence. Used at emit time so a record carries both the
+// fields a WithFields chain accumulated and the ones passed to the
+// Info/Warning/etc call itself.
+func mergeFields(logger *Logger, callSite Fields) Fields {
+	inherited := inheritedFields(logger)
+	if len(inherited) == 0 {
+		return callSite
+	}
+	if len(callSite) == 0 {
+		return inherited
+	}
+
+	merged := make(Fields, len(inherited)+len(callSite))
+	for k, v := range inherited {
+		merged[k] = v
+	}
+	for k, v := range callSite {
+		merged[k] = v
+	}
+	return merged
+}
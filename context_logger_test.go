@@ -0,0 +1,75 @@
+// Copyright 2016 Jim Zhang (jim.zoumo@gmail.com)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package logdog
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithFieldsMergesOverCallSite(t *testing.T) {
+	base := NewLogger()
+	child := base.WithFields(Fields{"request_id": "abc", "a": 1})
+
+	merged := mergeFields(child, Fields{"a": 2})
+	assert.Equal(t, "abc", merged["request_id"])
+	assert.Equal(t, 2, merged["a"])
+}
+
+// capturingHandler records the last LogRecord it was asked to handle, so
+// tests can assert on what a Logger actually emitted.
+type capturingHandler struct {
+	last *LogRecord
+}
+
+func (h *capturingHandler) Handle(record *LogRecord)      { h.last = record }
+func (h *capturingHandler) Filter(record *LogRecord) bool { return false }
+func (h *capturingHandler) Emit(record *LogRecord)        {}
+func (h *capturingHandler) Close() error                  { return nil }
+
+// TestWithFieldsAppearsInEmittedRecord is the end-to-end counterpart to
+// TestWithFieldsMergesOverCallSite: it drives a real child.Info(...) call
+// through a Handler and checks the record that comes out the other end,
+// rather than calling the private mergeFields helper directly.
+func TestWithFieldsAppearsInEmittedRecord(t *testing.T) {
+	capture := &capturingHandler{}
+	base := NewLogger().AddHandler(capture)
+	child := base.WithFields(Fields{"request_id": "abc", "a": 1})
+
+	child.Info("handled request", Fields{"a": 2})
+
+	assert.NotNil(t, capture.last)
+	assert.Equal(t, "handled request", capture.last.Message)
+	assert.Equal(t, "abc", capture.last.Fields["request_id"])
+	assert.Equal(t, 2, capture.last.Fields["a"])
+}
+
+func TestWithFieldsDoesNotLeak(t *testing.T) {
+	base := NewLogger()
+
+	for i := 0; i < 100000; i++ {
+		base.WithFields(Fields{"i": i})
+	}
+
+	runtime.GC()
+	runtime.GC()
+
+	loggerFieldsMu.Lock()
+	remaining := len(loggerFieldsMap)
+	loggerFieldsMu.Unlock()
+
+	assert.Less(t, remaining, 1000, "loggerFieldsMap should be reclaimed once its child Loggers are garbage")
+}
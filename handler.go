@@ -4,7 +4,7 @@
 // you may not use this file except in compliance with the License.
 // You may obtain a copy of the License at
 //
-//     http://www.apache.org/licenses/LICENSE-2.0
+//	http://www.apache.org/licenses/LICENSE-2.0
 //
 // Unless required by applicable law or agreed to in writing, software
 // distributed under the License is distributed on an "AS IS" BASIS,
@@ -52,7 +52,7 @@ func (hdlr *NullHandler) LoadConfig(config map[string]interface{}) error {
 	return nil
 }
 
-//Handle the specified record, filter and emit it
+// Handle the specified record, filter and emit it
 func (hdlr *NullHandler) Handle(*LogRecord) {
 	// do nothing
 }
@@ -166,11 +166,13 @@ type FileHandler struct {
 // NewFileHandler returns a new FileHandler fully initialized
 func NewFileHandler() *FileHandler {
 
-	return &FileHandler{
+	hdlr := &FileHandler{
 		Name:      "",
 		Level:     NOTHING,
 		Formatter: DefaultFormatter,
 	}
+	registerReopenable(hdlr)
+	return hdlr
 }
 
 // LoadConfig loads config from its input and
@@ -241,12 +243,33 @@ func (hdlr *FileHandler) Handle(record *LogRecord) {
 
 // Close file, if not return error
 func (hdlr *FileHandler) Close() error {
+	unregisterReopenable(hdlr)
 	if hdlr.Out == nil {
 		return nil
 	}
 	return hdlr.Out.Close()
 }
 
+// Reopen closes the currently open file and re-opens hdlr.Path, so that
+// logging resumes against the new inode after an external tool such as
+// logrotate has renamed or removed the old one.
+func (hdlr *FileHandler) Reopen() error {
+	hdlr.mu.Lock()
+	defer hdlr.mu.Unlock()
+
+	if hdlr.Out != nil {
+		hdlr.Out.Close()
+	}
+	hdlr.Out = nil
+
+	file, err := os.OpenFile(hdlr.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0660)
+	if err != nil {
+		return err
+	}
+	hdlr.Out = file
+	return nil
+}
+
 func init() {
 	RegisterConstructor("NullHandler", func() ConfigLoader {
 		return NewNullHandler()
@@ -0,0 +1,57 @@
+// Copyright 2016 Jim Zhang (jim.zoumo@gmail.com)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package logdog
+
+import "encoding/json"
+
+// jsonFormatter emits one JSON object per record, so a single record can
+// be consumed both by a human via TerminalFormatter and by a log-shipping
+// agent via this formatter.
+type jsonFormatter struct{}
+
+// JSONFormatter is the package's structured formatter, registered under
+// the name "json".
+var JSONFormatter Formatter = jsonFormatter{}
+
+// jsonRecord is the wire shape emitted by JSONFormatter.
+type jsonRecord struct {
+	Time   string `json:"timestamp"`
+	Level  string `json:"level"`
+	Logger string `json:"logger"`
+	Msg    string `json:"message"`
+	Caller string `json:"caller,omitempty"`
+	Fields Fields `json:"fields,omitempty"`
+}
+
+// Format renders record as a single JSON object.
+func (jsonFormatter) Format(record *LogRecord) (string, error) {
+	out := jsonRecord{
+		Time:   record.Time.Format("2006-01-02T15:04:05.000Z07:00"),
+		Level:  GetLevelName(record.Level),
+		Logger: record.Name,
+		Msg:    record.Message,
+		Caller: record.Caller,
+		Fields: record.Fields,
+	}
+
+	b, err := json.Marshal(out)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func init() {
+	RegisterFormatter("json", JSONFormatter)
+}
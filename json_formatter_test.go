@@ -0,0 +1,84 @@
+// Copyright 2016 Jim Zhang (jim.zoumo@gmail.com)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package logdog
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONFormatterShape(t *testing.T) {
+	record := &LogRecord{
+		Name:    "app",
+		Level:   WARNING,
+		Message: "disk almost full",
+		Time:    time.Date(2026, 7, 27, 12, 0, 0, 0, time.UTC),
+		Caller:  "main.go:42",
+		Fields:  Fields{"disk": "/dev/sda1"},
+	}
+
+	out, err := JSONFormatter.Format(record)
+	assert.Nil(t, err)
+
+	var decoded map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(out), &decoded))
+
+	assert.Equal(t, "2026-07-27T12:00:00.000Z", decoded["timestamp"])
+	assert.Equal(t, "WARNING", decoded["level"])
+	assert.Equal(t, "app", decoded["logger"])
+	assert.Equal(t, "disk almost full", decoded["message"])
+	assert.Equal(t, "main.go:42", decoded["caller"])
+	assert.Equal(t, map[string]interface{}{"disk": "/dev/sda1"}, decoded["fields"])
+}
+
+func TestJSONFormatterOmitsEmptyCallerAndFields(t *testing.T) {
+	record := &LogRecord{
+		Name:    "app",
+		Level:   INFO,
+		Message: "started",
+		Time:    time.Date(2026, 7, 27, 12, 0, 0, 0, time.UTC),
+	}
+
+	out, err := JSONFormatter.Format(record)
+	assert.Nil(t, err)
+
+	var decoded map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(out), &decoded))
+
+	_, hasCaller := decoded["caller"]
+	_, hasFields := decoded["fields"]
+	assert.False(t, hasCaller)
+	assert.False(t, hasFields)
+}
+
+func TestJSONFormatterWithInheritedFields(t *testing.T) {
+	capture := &capturingHandler{}
+	base := NewLogger().AddHandler(capture)
+	child := base.WithFields(Fields{"request_id": "abc"})
+
+	child.Info("handled request", Fields{"status": 200})
+
+	out, err := JSONFormatter.Format(capture.last)
+	assert.Nil(t, err)
+
+	var decoded map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(out), &decoded))
+
+	fields := decoded["fields"].(map[string]interface{})
+	assert.Equal(t, "abc", fields["request_id"])
+	assert.Equal(t, float64(200), fields["status"])
+}
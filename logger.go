@@ -0,0 +1,123 @@
+// Copyright 2016 Jim Zhang (jim.zoumo@gmail.com)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package logdog
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// Logger builds a LogRecord for each Debug/Info/Warning/Error/Critical
+// call and dispatches it to every attached Handler, letting each Handler
+// decide independently whether to filter or format it.
+type Logger struct {
+	Name     string
+	Level    int
+	Handlers []Handler
+
+	mu sync.Mutex
+}
+
+var (
+	loggersMu sync.Mutex
+	loggers   = map[string]*Logger{}
+)
+
+// NewLogger returns a new, unnamed Logger with no handlers attached.
+func NewLogger() *Logger {
+	return &Logger{Level: NOTHING}
+}
+
+// GetLogger returns the named Logger, creating and registering it on
+// first use so repeated calls with the same name share one Logger.
+func GetLogger(name string) *Logger {
+	loggersMu.Lock()
+	defer loggersMu.Unlock()
+
+	if logger, ok := loggers[name]; ok {
+		return logger
+	}
+	logger := &Logger{Name: name, Level: NOTHING}
+	loggers[name] = logger
+	return logger
+}
+
+// AddHandler attaches hdlr to logger and returns logger, for chaining.
+func (logger *Logger) AddHandler(hdlr Handler) *Logger {
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+	logger.Handlers = append(logger.Handlers, hdlr)
+	return logger
+}
+
+// log builds a LogRecord for msg at level - merging any fields inherited
+// from a WithFields/WithContext chain underneath whatever Fields were
+// passed at the call site, so the call site always wins on collisions -
+// and dispatches it to every attached Handler.
+func (logger *Logger) log(level int, msg string, fields ...Fields) {
+	if level < logger.Level {
+		return
+	}
+
+	var callSite Fields
+	if len(fields) > 0 {
+		callSite = fields[0]
+	}
+
+	record := &LogRecord{
+		Name:    logger.Name,
+		Level:   level,
+		Message: msg,
+		Time:    time.Now(),
+		Fields:  mergeFields(logger, callSite),
+	}
+	if _, file, line, ok := runtime.Caller(2); ok {
+		record.Caller = fmt.Sprintf("%s:%d", file, line)
+	}
+
+	logger.mu.Lock()
+	handlers := logger.Handlers
+	logger.mu.Unlock()
+
+	for _, hdlr := range handlers {
+		hdlr.Handle(record)
+	}
+}
+
+// Debug logs msg at DEBUG level.
+func (logger *Logger) Debug(msg string, fields ...Fields) {
+	logger.log(DEBUG, msg, fields...)
+}
+
+// Info logs msg at INFO level.
+func (logger *Logger) Info(msg string, fields ...Fields) {
+	logger.log(INFO, msg, fields...)
+}
+
+// Warning logs msg at WARNING level.
+func (logger *Logger) Warning(msg string, fields ...Fields) {
+	logger.log(WARNING, msg, fields...)
+}
+
+// Error logs msg at ERROR level.
+func (logger *Logger) Error(msg string, fields ...Fields) {
+	logger.log(ERROR, msg, fields...)
+}
+
+// Critical logs msg at CRITICAL level.
+func (logger *Logger) Critical(msg string, fields ...Fields) {
+	logger.log(CRITICAL, msg, fields...)
+}
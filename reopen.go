@@ -0,0 +1,89 @@
+// Copyright 2016 Jim Zhang (jim.zoumo@gmail.com)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package logdog
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+var (
+	reopenableMu       sync.Mutex
+	reopenableHandlers = make(map[*FileHandler]struct{})
+)
+
+// registerReopenable tracks hdlr so InstallSIGHUPReopen can reopen it
+// later. hdlr must be removed with unregisterReopenable once it is
+// closed, or it is tracked - and kept alive - for the life of the
+// process.
+func registerReopenable(hdlr *FileHandler) {
+	reopenableMu.Lock()
+	defer reopenableMu.Unlock()
+	reopenableHandlers[hdlr] = struct{}{}
+}
+
+// unregisterReopenable stops tracking hdlr, letting it be garbage
+// collected once nothing else references it.
+func unregisterReopenable(hdlr *FileHandler) {
+	reopenableMu.Lock()
+	defer reopenableMu.Unlock()
+	delete(reopenableHandlers, hdlr)
+}
+
+// InstallSIGHUPReopen starts a goroutine that calls Reopen on every
+// FileHandler created via NewFileHandler whenever the process receives
+// SIGHUP. This lets external tools like logrotate rename or remove the
+// log file and have logdog resume writing to the new inode without a
+// restart. It returns a function that stops listening for SIGHUP.
+func InstallSIGHUPReopen() func() {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGHUP)
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-c:
+				reopenAll()
+			case <-done:
+				signal.Stop(c)
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+	}
+}
+
+// reopenAll calls Reopen on every registered FileHandler, reporting
+// failures to stderr instead of aborting the remaining handlers.
+func reopenAll() {
+	reopenableMu.Lock()
+	handlers := make([]*FileHandler, 0, len(reopenableHandlers))
+	for hdlr := range reopenableHandlers {
+		handlers = append(handlers, hdlr)
+	}
+	reopenableMu.Unlock()
+
+	for _, hdlr := range handlers {
+		if err := hdlr.Reopen(); err != nil {
+			fmt.Fprintf(os.Stderr, "Reopen file %s failed, [%v]\n", hdlr.Path, err)
+		}
+	}
+}
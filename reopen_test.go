@@ -0,0 +1,77 @@
+// Copyright 2016 Jim Zhang (jim.zoumo@gmail.com)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package logdog
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileHandlerCloseUnregistersReopenable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reopen.log")
+	hdlr := NewFileHandler()
+	hdlr.Path = path
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0660)
+	assert.Nil(t, err)
+	hdlr.Out = file
+
+	reopenableMu.Lock()
+	_, tracked := reopenableHandlers[hdlr]
+	reopenableMu.Unlock()
+	assert.True(t, tracked, "NewFileHandler should register itself as reopenable")
+
+	assert.Nil(t, hdlr.Close())
+
+	reopenableMu.Lock()
+	_, tracked = reopenableHandlers[hdlr]
+	reopenableMu.Unlock()
+	assert.False(t, tracked, "Close should unregister the handler so it is not tracked forever")
+}
+
+func TestFileHandlerReopenNilsOutOnFailure(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "reopen.log")
+	hdlr := NewFileHandler()
+	hdlr.Path = path
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0660)
+	assert.Nil(t, err)
+	hdlr.Out = file
+	defer hdlr.Close()
+
+	// Point Path somewhere OpenFile cannot create, so the reopen fails.
+	hdlr.Path = filepath.Join(dir, "missing-parent", "reopen.log")
+
+	assert.NotNil(t, hdlr.Reopen())
+	assert.Nil(t, hdlr.Out, "a failed Reopen must not leave Out pointing at the already-closed file")
+}
+
+func TestReopenAllReopensRegisteredHandlers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reopen.log")
+	hdlr := NewFileHandler()
+	hdlr.Path = path
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0660)
+	assert.Nil(t, err)
+	hdlr.Out = file
+	defer hdlr.Close()
+
+	assert.Nil(t, os.Remove(path))
+
+	reopenAll()
+
+	_, err = os.Stat(path)
+	assert.Nil(t, err, "reopenAll should have recreated the removed log file")
+}
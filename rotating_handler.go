@@ -0,0 +1,420 @@
+// Copyright 2016 Jim Zhang (jim.zoumo@gmail.com)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package logdog
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zoumo/logdog/pkg/pythonic"
+)
+
+// RotatingFileHandler is a handler similar to FileHandler, except that
+// it rotates the underlying file once it reaches maxBytes, keeping up
+// to backupCount renamed copies suffixed ".1", ".2", and so on, with
+// ".1" always being the most recent backup.
+type RotatingFileHandler struct {
+	Path        string
+	Out         *os.File
+	MaxBytes    int64
+	BackupCount int
+
+	Name  string
+	Level int
+
+	Formatter Formatter
+	mu        sync.Mutex
+	size      int64
+	ConfigLoader
+}
+
+// NewRotatingFileHandler returns a new RotatingFileHandler fully initialized
+func NewRotatingFileHandler() *RotatingFileHandler {
+	return &RotatingFileHandler{
+		Name:        "",
+		Level:       NOTHING,
+		Formatter:   DefaultFormatter,
+		MaxBytes:    0,
+		BackupCount: 0,
+	}
+}
+
+// LoadConfig loads config from its input and
+// stores it in the value pointed to by c
+func (hdlr *RotatingFileHandler) LoadConfig(c map[string]interface{}) error {
+	config, err := pythonic.DictReflect(c)
+	if err != nil {
+		return err
+	}
+
+	hdlr.Name = config.MustGetString("name", "")
+
+	path := config.MustGetString("filename", "")
+	if path == "" {
+		return fmt.Errorf("Should provide a valid file path")
+	}
+	file, info, err := openAppend(path)
+	if err != nil {
+		panic(fmt.Errorf("Can not open file %s", path))
+	}
+	hdlr.Path = path
+	hdlr.Out = file
+	hdlr.size = info.Size()
+
+	hdlr.Level = GetLevelByName(config.MustGetString("level", "NOTHING"))
+
+	_formatter := config.MustGetString("formatter", "default")
+	formatter := GetFormatter(_formatter)
+	if formatter == nil {
+		return fmt.Errorf("can not find formatter: %s", _formatter)
+	}
+	hdlr.Formatter = formatter
+
+	hdlr.MaxBytes = config.MustGetInt64("maxBytes", 0)
+	hdlr.BackupCount = config.MustGetInt("backupCount", 0)
+
+	return nil
+}
+
+func openAppend(path string) (*os.File, os.FileInfo, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0660)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	return file, info, nil
+}
+
+// Emit log record to file, rotating it first if it has grown past MaxBytes
+func (hdlr *RotatingFileHandler) Emit(record *LogRecord) {
+	msg, err := hdlr.Formatter.Format(record)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Format record failed, [%v]\n", err)
+	}
+
+	if hdlr.shouldRollover(msg) {
+		if err := hdlr.doRollover(); err != nil {
+			fmt.Fprintf(os.Stderr, "Rotate file failed, [%v]\n", err)
+		}
+	}
+
+	n, _ := fmt.Fprintln(hdlr.Out, msg)
+	hdlr.size += int64(n)
+}
+
+// shouldRollover reports whether writing msg would push the file past MaxBytes
+func (hdlr *RotatingFileHandler) shouldRollover(msg string) bool {
+	if hdlr.MaxBytes <= 0 {
+		return false
+	}
+	return hdlr.size+int64(len(msg))+1 > hdlr.MaxBytes
+}
+
+// doRollover closes the current file, shifts the existing backups up by
+// one index and atomically re-opens Path for further writes.
+func (hdlr *RotatingFileHandler) doRollover() error {
+	if hdlr.Out != nil {
+		hdlr.Out.Close()
+	}
+	hdlr.Out = nil
+
+	if hdlr.BackupCount > 0 {
+		for i := hdlr.BackupCount - 1; i > 0; i-- {
+			src := fmt.Sprintf("%s.%d", hdlr.Path, i)
+			dst := fmt.Sprintf("%s.%d", hdlr.Path, i+1)
+			if _, err := os.Stat(src); err == nil {
+				os.Rename(src, dst)
+			}
+		}
+		os.Rename(hdlr.Path, hdlr.Path+".1")
+	}
+
+	file, err := os.OpenFile(hdlr.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0660)
+	if err != nil {
+		return err
+	}
+	hdlr.Out = file
+	hdlr.size = 0
+	return nil
+}
+
+// Filter check if handler should filter the specified record
+func (hdlr *RotatingFileHandler) Filter(record *LogRecord) bool {
+	if record.Level < hdlr.Level {
+		return true
+	}
+	return false
+}
+
+// Handle the specified record, filter and emit it
+func (hdlr *RotatingFileHandler) Handle(record *LogRecord) {
+	if hdlr.Out == nil {
+		panic("you should set output file before use this handler")
+	}
+	filtered := hdlr.Filter(record)
+	if !filtered {
+		hdlr.mu.Lock()
+		defer hdlr.mu.Unlock()
+		hdlr.Emit(record)
+	}
+}
+
+// Close file, if not return error
+func (hdlr *RotatingFileHandler) Close() error {
+	if hdlr.Out == nil {
+		return nil
+	}
+	return hdlr.Out.Close()
+}
+
+// rolloverWhen identifies the unit used to schedule TimedRotatingFileHandler rollovers
+type rolloverWhen string
+
+const (
+	whenSecond   rolloverWhen = "S"
+	whenMinute   rolloverWhen = "M"
+	whenHour     rolloverWhen = "H"
+	whenDay      rolloverWhen = "D"
+	whenMidnight rolloverWhen = "MIDNIGHT"
+)
+
+// TimedRotatingFileHandler is a handler similar to FileHandler, except
+// that it rotates the underlying file on a fixed time interval,
+// suffixing each backup with the timestamp at which it was closed.
+type TimedRotatingFileHandler struct {
+	Path        string
+	Out         *os.File
+	When        rolloverWhen
+	Interval    time.Duration
+	BackupCount int
+	UTC         bool
+
+	Name  string
+	Level int
+
+	Formatter  Formatter
+	mu         sync.Mutex
+	rolloverAt time.Time
+	ConfigLoader
+}
+
+// NewTimedRotatingFileHandler returns a new TimedRotatingFileHandler fully initialized
+func NewTimedRotatingFileHandler() *TimedRotatingFileHandler {
+	return &TimedRotatingFileHandler{
+		Name:        "",
+		Level:       NOTHING,
+		Formatter:   DefaultFormatter,
+		When:        whenDay,
+		Interval:    time.Hour * 24,
+		BackupCount: 0,
+	}
+}
+
+// LoadConfig loads config from its input and
+// stores it in the value pointed to by c
+func (hdlr *TimedRotatingFileHandler) LoadConfig(c map[string]interface{}) error {
+	config, err := pythonic.DictReflect(c)
+	if err != nil {
+		return err
+	}
+
+	hdlr.Name = config.MustGetString("name", "")
+
+	path := config.MustGetString("filename", "")
+	if path == "" {
+		return fmt.Errorf("Should provide a valid file path")
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0660)
+	if err != nil {
+		panic(fmt.Errorf("Can not open file %s", path))
+	}
+	hdlr.Path = path
+	hdlr.Out = file
+
+	hdlr.Level = GetLevelByName(config.MustGetString("level", "NOTHING"))
+
+	_formatter := config.MustGetString("formatter", "default")
+	formatter := GetFormatter(_formatter)
+	if formatter == nil {
+		return fmt.Errorf("can not find formatter: %s", _formatter)
+	}
+	hdlr.Formatter = formatter
+
+	hdlr.UTC = config.MustGetBool("utc", false)
+	hdlr.BackupCount = config.MustGetInt("backupCount", 0)
+
+	when, interval, err := parseWhen(
+		config.MustGetString("when", "D"),
+		config.MustGetInt("interval", 1),
+	)
+	if err != nil {
+		return err
+	}
+	hdlr.When = when
+	hdlr.Interval = interval
+	hdlr.rolloverAt = hdlr.nextRolloverTime(hdlr.now())
+
+	return nil
+}
+
+func parseWhen(when string, interval int) (rolloverWhen, time.Duration, error) {
+	w := rolloverWhen(strings.ToUpper(when))
+	switch w {
+	case whenSecond:
+		return w, time.Duration(interval) * time.Second, nil
+	case whenMinute:
+		return w, time.Duration(interval) * time.Minute, nil
+	case whenHour:
+		return w, time.Duration(interval) * time.Hour, nil
+	case whenDay:
+		return w, time.Duration(interval) * 24 * time.Hour, nil
+	case whenMidnight:
+		return w, 24 * time.Hour, nil
+	default:
+		return "", 0, fmt.Errorf("unknown rollover interval: %s", when)
+	}
+}
+
+func (hdlr *TimedRotatingFileHandler) now() time.Time {
+	if hdlr.UTC {
+		return time.Now().UTC()
+	}
+	return time.Now()
+}
+
+// nextRolloverTime computes the next rollover deadline after t
+func (hdlr *TimedRotatingFileHandler) nextRolloverTime(t time.Time) time.Time {
+	if hdlr.When == whenMidnight {
+		year, month, day := t.Date()
+		return time.Date(year, month, day, 0, 0, 0, 0, t.Location()).Add(24 * time.Hour)
+	}
+	return t.Add(hdlr.Interval)
+}
+
+// Emit log record to file, rotating it first if the rollover deadline has passed
+func (hdlr *TimedRotatingFileHandler) Emit(record *LogRecord) {
+	now := hdlr.now()
+	if !now.Before(hdlr.rolloverAt) {
+		if err := hdlr.doRollover(now); err != nil {
+			fmt.Fprintf(os.Stderr, "Rotate file failed, [%v]\n", err)
+		}
+	}
+
+	msg, err := hdlr.Formatter.Format(record)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Format record failed, [%v]\n", err)
+	}
+	fmt.Fprintln(hdlr.Out, msg)
+}
+
+// doRollover closes the current file, renames it with a timestamp suffix,
+// prunes backups past BackupCount and atomically re-opens Path.
+func (hdlr *TimedRotatingFileHandler) doRollover(now time.Time) error {
+	if hdlr.Out != nil {
+		hdlr.Out.Close()
+	}
+	hdlr.Out = nil
+
+	backup := fmt.Sprintf("%s.%s", hdlr.Path, now.Format("2006-01-02_15-04-05"))
+	if err := os.Rename(hdlr.Path, backup); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if hdlr.BackupCount > 0 {
+		hdlr.pruneBackups()
+	}
+
+	file, err := os.OpenFile(hdlr.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0660)
+	if err != nil {
+		return err
+	}
+	hdlr.Out = file
+	hdlr.rolloverAt = hdlr.nextRolloverTime(now)
+	return nil
+}
+
+// pruneBackups removes the oldest timestamped backups, keeping BackupCount of them
+func (hdlr *TimedRotatingFileHandler) pruneBackups() {
+	dir := "."
+	base := hdlr.Path
+	if idx := strings.LastIndex(hdlr.Path, string(os.PathSeparator)); idx >= 0 {
+		dir = hdlr.Path[:idx]
+		base = hdlr.Path[idx+1:]
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	var backups []string
+	prefix := base + "."
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), prefix) {
+			backups = append(backups, e.Name())
+		}
+	}
+	sort.Strings(backups)
+
+	for len(backups) > hdlr.BackupCount {
+		os.Remove(dir + string(os.PathSeparator) + backups[0])
+		backups = backups[1:]
+	}
+}
+
+// Filter check if handler should filter the specified record
+func (hdlr *TimedRotatingFileHandler) Filter(record *LogRecord) bool {
+	if record.Level < hdlr.Level {
+		return true
+	}
+	return false
+}
+
+// Handle the specified record, filter and emit it
+func (hdlr *TimedRotatingFileHandler) Handle(record *LogRecord) {
+	if hdlr.Out == nil {
+		panic("you should set output file before use this handler")
+	}
+	filtered := hdlr.Filter(record)
+	if !filtered {
+		hdlr.mu.Lock()
+		defer hdlr.mu.Unlock()
+		hdlr.Emit(record)
+	}
+}
+
+// Close file, if not return error
+func (hdlr *TimedRotatingFileHandler) Close() error {
+	if hdlr.Out == nil {
+		return nil
+	}
+	return hdlr.Out.Close()
+}
+
+func init() {
+	RegisterConstructor("RotatingFileHandler", func() ConfigLoader {
+		return NewRotatingFileHandler()
+	})
+	RegisterConstructor("TimedRotatingFileHandler", func() ConfigLoader {
+		return NewTimedRotatingFileHandler()
+	})
+}
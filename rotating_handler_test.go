@@ -0,0 +1,137 @@
+// Copyright 2016 Jim Zhang (jim.zoumo@gmail.com)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package logdog
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestRotatingFileHandler(t *testing.T, maxBytes int64, backupCount int) (*RotatingFileHandler, string) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	file, info, err := openAppend(path)
+	assert.Nil(t, err)
+
+	hdlr := NewRotatingFileHandler()
+	hdlr.Path = path
+	hdlr.Out = file
+	hdlr.size = info.Size()
+	hdlr.MaxBytes = maxBytes
+	hdlr.BackupCount = backupCount
+	hdlr.Formatter = DefaultFormatter
+	return hdlr, path
+}
+
+func TestRotatingFileHandlerRollsOverPastMaxBytes(t *testing.T) {
+	hdlr, path := newTestRotatingFileHandler(t, 20, 2)
+	defer hdlr.Close()
+
+	hdlr.Emit(&LogRecord{Message: "short"})
+	assert.NotNil(t, hdlr.Out)
+
+	if _, err := os.Stat(path + ".1"); err == nil {
+		t.Fatal("should not have rolled over before reaching MaxBytes")
+	}
+
+	hdlr.Emit(&LogRecord{Message: "this line pushes us well past the limit"})
+
+	_, err := os.Stat(path + ".1")
+	assert.Nil(t, err, "RotatingFileHandler should have rolled the file to .1 once MaxBytes was exceeded")
+}
+
+func TestRotatingFileHandlerShiftsBackups(t *testing.T) {
+	hdlr, path := newTestRotatingFileHandler(t, 1, 3)
+	defer hdlr.Close()
+
+	assert.Nil(t, os.WriteFile(path+".1", []byte("newer backup"), 0660))
+	assert.Nil(t, os.WriteFile(path+".2", []byte("older backup"), 0660))
+
+	assert.Nil(t, hdlr.doRollover())
+
+	data1, err := os.ReadFile(path + ".1")
+	assert.Nil(t, err)
+	assert.Empty(t, string(data1))
+
+	data2, err := os.ReadFile(path + ".2")
+	assert.Nil(t, err)
+	assert.Equal(t, "newer backup", string(data2))
+
+	data3, err := os.ReadFile(path + ".3")
+	assert.Nil(t, err)
+	assert.Equal(t, "older backup", string(data3))
+}
+
+func newTestTimedRotatingFileHandler(t *testing.T, backupCount int) (*TimedRotatingFileHandler, string) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0660)
+	assert.Nil(t, err)
+
+	hdlr := NewTimedRotatingFileHandler()
+	hdlr.Path = path
+	hdlr.Out = file
+	hdlr.BackupCount = backupCount
+	hdlr.Formatter = DefaultFormatter
+	hdlr.When = whenSecond
+	hdlr.Interval = time.Second
+	hdlr.rolloverAt = hdlr.nextRolloverTime(hdlr.now())
+	return hdlr, path
+}
+
+func TestTimedRotatingFileHandlerDoRolloverRecreatesPath(t *testing.T) {
+	hdlr, path := newTestTimedRotatingFileHandler(t, 0)
+	defer hdlr.Close()
+
+	now := hdlr.now()
+	assert.Nil(t, hdlr.doRollover(now.Add(2*time.Second)))
+	assert.NotNil(t, hdlr.Out)
+
+	_, err := os.Stat(path)
+	assert.Nil(t, err, "doRollover should have re-created the live file at Path")
+}
+
+func TestTimedRotatingFileHandlerPrunesBackups(t *testing.T) {
+	hdlr, _ := newTestTimedRotatingFileHandler(t, 1)
+	defer hdlr.Close()
+
+	now := hdlr.now()
+	assert.Nil(t, hdlr.doRollover(now))
+	assert.Nil(t, hdlr.doRollover(now.Add(time.Second)))
+	assert.Nil(t, hdlr.doRollover(now.Add(2*time.Second)))
+
+	dir := filepath.Dir(hdlr.Path)
+	entries, err := os.ReadDir(dir)
+	assert.Nil(t, err)
+
+	var backups int
+	for _, e := range entries {
+		if e.Name() != filepath.Base(hdlr.Path) {
+			backups++
+		}
+	}
+	assert.Equal(t, 1, backups, "pruneBackups should keep only BackupCount timestamped backups")
+}
+
+func TestNextRolloverTimeMidnight(t *testing.T) {
+	hdlr := NewTimedRotatingFileHandler()
+	hdlr.When = whenMidnight
+
+	now := time.Date(2026, 7, 27, 15, 30, 0, 0, time.UTC)
+	next := hdlr.nextRolloverTime(now)
+
+	assert.Equal(t, time.Date(2026, 7, 28, 0, 0, 0, 0, time.UTC), next)
+}
@@ -0,0 +1,218 @@
+// Copyright 2016 Jim Zhang (jim.zoumo@gmail.com)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package logdog
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zoumo/logdog/pkg/pythonic"
+)
+
+// sampleKey selects what identifies a "bucket" of records for SampledHandler
+type sampleKey string
+
+const (
+	sampleKeyMessage sampleKey = "message"
+	sampleKeyCaller  sampleKey = "caller"
+	sampleKeyLevel   sampleKey = "level"
+)
+
+// sampleBucket tracks how many records a given key has seen in the
+// current window.
+type sampleBucket struct {
+	windowStart time.Time
+	count       int64
+}
+
+// SampledHandler wraps another Handler and throttles high-volume log
+// sites using the "first N then every Mth" scheme popularized by
+// glog/zap: within each Interval window, the first Initial matching
+// records pass through, then only every Thereafter-th one does.
+type SampledHandler struct {
+	Target     Handler
+	Initial    int
+	Thereafter int
+	Interval   time.Duration
+	Key        sampleKey
+
+	Name  string
+	Level int
+
+	// Dropped counts the records this handler has suppressed, so
+	// operators can observe how aggressively it is throttling.
+	Dropped uint64
+
+	mu        sync.Mutex
+	buckets   map[string]*sampleBucket
+	lastSweep time.Time
+	ConfigLoader
+}
+
+// NewSampledHandler returns a new SampledHandler fully initialized
+func NewSampledHandler() *SampledHandler {
+	return &SampledHandler{
+		Name:       "",
+		Level:      NOTHING,
+		Initial:    10,
+		Thereafter: 100,
+		Interval:   time.Second,
+		Key:        sampleKeyMessage,
+		buckets:    make(map[string]*sampleBucket),
+	}
+}
+
+// LoadConfig loads config from its input and
+// stores it in the value pointed to by c
+func (hdlr *SampledHandler) LoadConfig(c map[string]interface{}) error {
+	config, err := pythonic.DictReflect(c)
+	if err != nil {
+		return err
+	}
+
+	hdlr.Name = config.MustGetString("name", "")
+	hdlr.Level = GetLevelByName(config.MustGetString("level", "NOTHING"))
+
+	target := config.MustGetString("target", "")
+	if target == "" {
+		return fmt.Errorf("Should provide a target handler name")
+	}
+	wrapped := GetHandler(target)
+	if wrapped == nil {
+		return fmt.Errorf("can not find handler: %s", target)
+	}
+	hdlr.Target = wrapped
+
+	hdlr.Initial = config.MustGetInt("initial", 10)
+	hdlr.Thereafter = config.MustGetInt("thereafter", 100)
+
+	interval := config.MustGetString("interval", "1s")
+	d, err := time.ParseDuration(interval)
+	if err != nil {
+		return fmt.Errorf("invalid sampling interval %q: %v", interval, err)
+	}
+	hdlr.Interval = d
+
+	key := sampleKey(config.MustGetString("key", "message"))
+	switch key {
+	case sampleKeyMessage, sampleKeyCaller, sampleKeyLevel:
+		hdlr.Key = key
+	default:
+		return fmt.Errorf("unknown sampling key: %s", key)
+	}
+
+	return nil
+}
+
+// bucketKey extracts the identity SampledHandler buckets record under
+func (hdlr *SampledHandler) bucketKey(record *LogRecord) string {
+	switch hdlr.Key {
+	case sampleKeyCaller:
+		return record.Caller
+	case sampleKeyLevel:
+		return strconv.Itoa(record.Level)
+	default:
+		return record.Message
+	}
+}
+
+// shouldLog reports whether record should pass through, per the
+// "first Initial then every Thereafter-th" scheme.
+func (hdlr *SampledHandler) shouldLog(record *LogRecord) bool {
+	hdlr.mu.Lock()
+	defer hdlr.mu.Unlock()
+
+	key := hdlr.bucketKey(record)
+	now := time.Now()
+
+	hdlr.sweepStaleBuckets(now)
+
+	bucket, ok := hdlr.buckets[key]
+	if !ok || now.Sub(bucket.windowStart) >= hdlr.Interval {
+		bucket = &sampleBucket{windowStart: now}
+		hdlr.buckets[key] = bucket
+	}
+
+	bucket.count++
+
+	if bucket.count <= int64(hdlr.Initial) {
+		return true
+	}
+	if hdlr.Thereafter <= 0 {
+		return false
+	}
+	return (bucket.count-int64(hdlr.Initial))%int64(hdlr.Thereafter) == 0
+}
+
+// staleAfter is how many Intervals a bucket can sit unused before
+// sweepStaleBuckets reclaims it. Buckets for keys derived from variable message content
+// (the default "message" sampling key) would otherwise grow the map
+// without bound for the life of the process.
+const staleAfter = 10
+
+// sweepStaleBuckets drops buckets whose window closed long ago, amortized
+// to run at most once per Interval rather than on every call. Called with
+// hdlr.mu already held.
+func (hdlr *SampledHandler) sweepStaleBuckets(now time.Time) {
+	if now.Sub(hdlr.lastSweep) < hdlr.Interval {
+		return
+	}
+	hdlr.lastSweep = now
+
+	cutoff := staleAfter * hdlr.Interval
+	for key, bucket := range hdlr.buckets {
+		if now.Sub(bucket.windowStart) >= cutoff {
+			delete(hdlr.buckets, key)
+		}
+	}
+}
+
+// Filter check if handler should filter the specified record
+func (hdlr *SampledHandler) Filter(record *LogRecord) bool {
+	if record.Level < hdlr.Level {
+		return true
+	}
+	return false
+}
+
+// Handle the specified record, filter, sample and emit it
+func (hdlr *SampledHandler) Handle(record *LogRecord) {
+	if hdlr.Filter(record) {
+		return
+	}
+	if !hdlr.shouldLog(record) {
+		atomic.AddUint64(&hdlr.Dropped, 1)
+		return
+	}
+	hdlr.Target.Handle(record)
+}
+
+// Emit log record via the wrapped Target handler
+func (hdlr *SampledHandler) Emit(record *LogRecord) {
+	hdlr.Target.Emit(record)
+}
+
+// Close the wrapped handler, if not return error
+func (hdlr *SampledHandler) Close() error {
+	return hdlr.Target.Close()
+}
+
+func init() {
+	RegisterConstructor("SampledHandler", func() ConfigLoader {
+		return NewSampledHandler()
+	})
+}
@@ -0,0 +1,72 @@
+// Copyright 2016 Jim Zhang (jim.zoumo@gmail.com)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package logdog
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSampledHandlerFirstNThenEveryMth(t *testing.T) {
+	hdlr := NewSampledHandler()
+	hdlr.Initial = 2
+	hdlr.Thereafter = 3
+	hdlr.Interval = time.Hour
+
+	record := &LogRecord{Level: INFO, Message: "boom"}
+	var passed []bool
+	for i := 0; i < 8; i++ {
+		passed = append(passed, hdlr.shouldLog(record))
+	}
+
+	assert.Equal(t, []bool{true, true, false, false, true, false, false, true}, passed)
+}
+
+func TestSampledHandlerDroppedIsPerHandler(t *testing.T) {
+	a := NewSampledHandler()
+	a.Target = NewNullHandler()
+	a.Initial = 1
+	a.Thereafter = 0
+
+	b := NewSampledHandler()
+	b.Target = NewNullHandler()
+	b.Initial = 1
+	b.Thereafter = 0
+
+	for i := 0; i < 5; i++ {
+		a.Handle(&LogRecord{Level: INFO, Message: "boom"})
+	}
+
+	assert.Equal(t, uint64(4), a.Dropped)
+	assert.Equal(t, uint64(0), b.Dropped, "a second handler's Dropped counter must not be affected by another instance")
+}
+
+func TestSampledHandlerPrunesStaleBuckets(t *testing.T) {
+	hdlr := NewSampledHandler()
+	hdlr.Interval = time.Millisecond
+
+	for i := 0; i < 1000; i++ {
+		hdlr.shouldLog(&LogRecord{Level: INFO, Message: fmt.Sprintf("msg-%d", i)})
+	}
+	assert.Len(t, hdlr.buckets, 1000)
+
+	time.Sleep(staleAfter * time.Millisecond * 2)
+	hdlr.shouldLog(&LogRecord{Level: INFO, Message: "trigger-sweep"})
+
+	assert.Less(t, len(hdlr.buckets), 1000,
+		"buckets for keys that stopped appearing should eventually be reclaimed")
+}
@@ -0,0 +1,215 @@
+// Copyright 2016 Jim Zhang (jim.zoumo@gmail.com)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package logdog
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sync"
+
+	"github.com/zoumo/logdog/pkg/pythonic"
+)
+
+// maxRetryBuffer bounds how many formatted records SocketHandler keeps
+// in memory while the connection to the peer is down.
+const maxRetryBuffer = 1024
+
+// SocketHandler ships formatted log records over a tcp, udp or unix
+// socket. If the connection drops it reconnects lazily on the next
+// Emit and replays records buffered while disconnected, up to
+// maxRetryBuffer.
+type SocketHandler struct {
+	Address string
+	Network string
+
+	Name  string
+	Level int
+
+	Formatter Formatter
+	conn      net.Conn
+	retry     [][]byte
+	mu        sync.Mutex
+	ConfigLoader
+}
+
+// NewSocketHandler returns a new SocketHandler fully initialized
+func NewSocketHandler() *SocketHandler {
+	return &SocketHandler{
+		Name:      "",
+		Level:     NOTHING,
+		Network:   "tcp",
+		Formatter: DefaultFormatter,
+	}
+}
+
+// LoadConfig loads config from its input and
+// stores it in the value pointed to by c
+func (hdlr *SocketHandler) LoadConfig(c map[string]interface{}) error {
+	config, err := pythonic.DictReflect(c)
+	if err != nil {
+		return err
+	}
+
+	hdlr.Name = config.MustGetString("name", "")
+	hdlr.Level = GetLevelByName(config.MustGetString("level", "NOTHING"))
+
+	_formatter := config.MustGetString("formatter", "default")
+	formatter := GetFormatter(_formatter)
+	if formatter == nil {
+		return fmt.Errorf("can not find formatter: %s", _formatter)
+	}
+	hdlr.Formatter = formatter
+
+	hdlr.Network = config.MustGetString("network", "tcp")
+	hdlr.Address = config.MustGetString("address", "")
+	if hdlr.Address == "" {
+		return fmt.Errorf("Should provide a valid address")
+	}
+
+	// connection is established lazily on first Emit so a temporarily
+	// unreachable peer does not fail LoadConfig
+	return nil
+}
+
+// connect dials the peer, reconnecting if a previous connection was lost
+func (hdlr *SocketHandler) connect() error {
+	if hdlr.conn != nil {
+		return nil
+	}
+	conn, err := net.Dial(hdlr.Network, hdlr.Address)
+	if err != nil {
+		return err
+	}
+	hdlr.conn = conn
+	return nil
+}
+
+// buffer stores data for replay once the connection comes back, dropping
+// the oldest entry once maxRetryBuffer is exceeded
+func (hdlr *SocketHandler) buffer(data []byte) {
+	if len(hdlr.retry) >= maxRetryBuffer {
+		hdlr.retry = hdlr.retry[1:]
+	}
+	hdlr.retry = append(hdlr.retry, data)
+}
+
+// flushRetryBuffer resends any records accumulated while disconnected.
+// It stops at the first failed write instead of continuing the loop,
+// since a failed write means hdlr.conn is gone - continuing would call
+// Write on a nil connection on the next iteration.
+func (hdlr *SocketHandler) flushRetryBuffer() {
+	sent := 0
+	for _, data := range hdlr.retry {
+		if _, err := hdlr.conn.Write(data); err != nil {
+			hdlr.conn = nil
+			break
+		}
+		sent++
+	}
+
+	remaining := make([][]byte, len(hdlr.retry)-sent)
+	copy(remaining, hdlr.retry[sent:])
+	hdlr.retry = remaining
+}
+
+// Emit log record over the socket, reconnecting and replaying buffered
+// records if needed
+func (hdlr *SocketHandler) Emit(record *LogRecord) {
+	msg, err := hdlr.Formatter.Format(record)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Format record failed, [%v]\n", err)
+	}
+	data := []byte(msg + "\n")
+
+	if err := hdlr.connect(); err != nil {
+		hdlr.buffer(data)
+		return
+	}
+
+	if len(hdlr.retry) > 0 {
+		hdlr.flushRetryBuffer()
+		if hdlr.conn == nil {
+			hdlr.buffer(data)
+			return
+		}
+	}
+
+	if _, err := hdlr.conn.Write(data); err != nil {
+		hdlr.conn = nil
+		hdlr.buffer(data)
+	}
+}
+
+// Filter check if handler should filter the specified record
+func (hdlr *SocketHandler) Filter(record *LogRecord) bool {
+	if record.Level < hdlr.Level {
+		return true
+	}
+	return false
+}
+
+// Handle the specified record, filter and emit it
+func (hdlr *SocketHandler) Handle(record *LogRecord) {
+	filtered := hdlr.Filter(record)
+	if !filtered {
+		hdlr.mu.Lock()
+		defer hdlr.mu.Unlock()
+		hdlr.Emit(record)
+	}
+}
+
+// Close the socket, if not return error
+func (hdlr *SocketHandler) Close() error {
+	if hdlr.conn == nil {
+		return nil
+	}
+	return hdlr.conn.Close()
+}
+
+// DatagramHandler is a SocketHandler that defaults to udp, the
+// connectionless counterpart used for fire-and-forget shipping.
+type DatagramHandler struct {
+	SocketHandler
+}
+
+// NewDatagramHandler returns a new DatagramHandler fully initialized
+func NewDatagramHandler() *DatagramHandler {
+	hdlr := &DatagramHandler{}
+	hdlr.Name = ""
+	hdlr.Level = NOTHING
+	hdlr.Network = "udp"
+	hdlr.Formatter = DefaultFormatter
+	return hdlr
+}
+
+// LoadConfig loads config from its input and
+// stores it in the value pointed to by c. It is identical to
+// SocketHandler.LoadConfig except for the default network, so it
+// delegates to it after filling that default in.
+func (hdlr *DatagramHandler) LoadConfig(c map[string]interface{}) error {
+	if _, ok := c["network"]; !ok {
+		c["network"] = "udp"
+	}
+	return hdlr.SocketHandler.LoadConfig(c)
+}
+
+func init() {
+	RegisterConstructor("SocketHandler", func() ConfigLoader {
+		return NewSocketHandler()
+	})
+	RegisterConstructor("DatagramHandler", func() ConfigLoader {
+		return NewDatagramHandler()
+	})
+}
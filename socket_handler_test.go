@@ -0,0 +1,78 @@
+// Copyright 2016 Jim Zhang (jim.zoumo@gmail.com)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package logdog
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// failAfterNConn is a net.Conn stub whose Write starts failing after N
+// successful calls, used to simulate a connection that drops mid-flush.
+type failAfterNConn struct {
+	remainingOK int
+}
+
+func (c *failAfterNConn) Write(b []byte) (int, error) {
+	if c.remainingOK <= 0 {
+		return 0, errors.New("connection reset by peer")
+	}
+	c.remainingOK--
+	return len(b), nil
+}
+
+func (c *failAfterNConn) Read(b []byte) (int, error)         { return 0, nil }
+func (c *failAfterNConn) Close() error                       { return nil }
+func (c *failAfterNConn) LocalAddr() net.Addr                { return nil }
+func (c *failAfterNConn) RemoteAddr() net.Addr               { return nil }
+func (c *failAfterNConn) SetDeadline(t time.Time) error      { return nil }
+func (c *failAfterNConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *failAfterNConn) SetWriteDeadline(t time.Time) error { return nil }
+
+func TestDatagramHandlerLoadConfigDefaultsToUDP(t *testing.T) {
+	hdlr := NewDatagramHandler()
+	err := hdlr.LoadConfig(map[string]interface{}{
+		"address": "localhost:514",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "udp", hdlr.Network)
+}
+
+func TestDatagramHandlerLoadConfigHonorsExplicitNetwork(t *testing.T) {
+	hdlr := NewDatagramHandler()
+	err := hdlr.LoadConfig(map[string]interface{}{
+		"address": "localhost:514",
+		"network": "unixgram",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "unixgram", hdlr.Network)
+}
+
+func TestSocketHandlerFlushRetryBufferStopsOnFirstFailure(t *testing.T) {
+	hdlr := NewSocketHandler()
+	hdlr.conn = &failAfterNConn{remainingOK: 1}
+	hdlr.retry = [][]byte{[]byte("one\n"), []byte("two\n"), []byte("three\n")}
+
+	assert.NotPanics(t, func() {
+		hdlr.flushRetryBuffer()
+	})
+
+	assert.Nil(t, hdlr.conn, "conn should be cleared once a write fails")
+	assert.Equal(t, [][]byte{[]byte("two\n"), []byte("three\n")}, hdlr.retry,
+		"records from the failed write onward should stay buffered for the next reconnect")
+}
@@ -0,0 +1,232 @@
+// Copyright 2016 Jim Zhang (jim.zoumo@gmail.com)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package logdog
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/zoumo/logdog/pkg/pythonic"
+)
+
+// syslog facility codes, as defined by RFC 5424
+const (
+	facilityKern     = 0
+	facilityUser     = 1
+	facilityMail     = 2
+	facilityDaemon   = 3
+	facilityAuth     = 4
+	facilitySyslog   = 5
+	facilityLPR      = 6
+	facilityNews     = 7
+	facilityUUCP     = 8
+	facilityCron     = 9
+	facilityAuthpriv = 10
+	facilityFTP      = 11
+	facilityLocal0   = 16
+	facilityLocal1   = 17
+	facilityLocal2   = 18
+	facilityLocal3   = 19
+	facilityLocal4   = 20
+	facilityLocal5   = 21
+	facilityLocal6   = 22
+	facilityLocal7   = 23
+)
+
+var syslogFacilities = map[string]int{
+	"kern":     facilityKern,
+	"user":     facilityUser,
+	"mail":     facilityMail,
+	"daemon":   facilityDaemon,
+	"auth":     facilityAuth,
+	"syslog":   facilitySyslog,
+	"lpr":      facilityLPR,
+	"news":     facilityNews,
+	"uucp":     facilityUUCP,
+	"cron":     facilityCron,
+	"authpriv": facilityAuthpriv,
+	"ftp":      facilityFTP,
+	"local0":   facilityLocal0,
+	"local1":   facilityLocal1,
+	"local2":   facilityLocal2,
+	"local3":   facilityLocal3,
+	"local4":   facilityLocal4,
+	"local5":   facilityLocal5,
+	"local6":   facilityLocal6,
+	"local7":   facilityLocal7,
+}
+
+// syslogSeverity maps a LogRecord level to the RFC 5424 severity it corresponds to
+func syslogSeverity(level int) int {
+	switch {
+	case level >= CRITICAL:
+		return 2 // crit
+	case level >= ERROR:
+		return 3 // err
+	case level >= WARNING:
+		return 4 // warning
+	case level >= INFO:
+		return 6 // informational
+	default:
+		return 7 // debug
+	}
+}
+
+// SyslogHandler writes records to a local or remote syslog daemon using
+// RFC 5424 framing.
+type SyslogHandler struct {
+	Address  string
+	Network  string
+	Facility int
+	Tag      string
+	Hostname string
+
+	Name  string
+	Level int
+
+	Formatter Formatter
+	conn      net.Conn
+	mu        sync.Mutex
+	ConfigLoader
+}
+
+// NewSyslogHandler returns a new SyslogHandler fully initialized
+func NewSyslogHandler() *SyslogHandler {
+	hostname, _ := os.Hostname()
+	return &SyslogHandler{
+		Name:     "",
+		Level:    NOTHING,
+		Network:  "udp",
+		Address:  "localhost:514",
+		Facility: facilityUser,
+		Tag:      os.Args[0],
+		Hostname: hostname,
+	}
+}
+
+// LoadConfig loads config from its input and
+// stores it in the value pointed to by c
+func (hdlr *SyslogHandler) LoadConfig(c map[string]interface{}) error {
+	config, err := pythonic.DictReflect(c)
+	if err != nil {
+		return err
+	}
+
+	hdlr.Name = config.MustGetString("name", "")
+	hdlr.Level = GetLevelByName(config.MustGetString("level", "NOTHING"))
+
+	_formatter := config.MustGetString("formatter", "default")
+	formatter := GetFormatter(_formatter)
+	if formatter == nil {
+		return fmt.Errorf("can not find formatter: %s", _formatter)
+	}
+	hdlr.Formatter = formatter
+
+	hdlr.Network = config.MustGetString("network", "udp")
+	hdlr.Address = config.MustGetString("address", "localhost:514")
+	hdlr.Tag = config.MustGetString("tag", os.Args[0])
+
+	facility := config.MustGetString("facility", "user")
+	code, ok := syslogFacilities[facility]
+	if !ok {
+		return fmt.Errorf("unknown syslog facility: %s", facility)
+	}
+	hdlr.Facility = code
+
+	if hdlr.Hostname == "" {
+		hdlr.Hostname, _ = os.Hostname()
+	}
+
+	// connection is established lazily on first Emit, like SocketHandler,
+	// so a temporarily unreachable syslog daemon does not fail LoadConfig
+	return nil
+}
+
+// connect dials the syslog daemon, reconnecting if a previous connection
+// was lost.
+func (hdlr *SyslogHandler) connect() error {
+	if hdlr.conn != nil {
+		return nil
+	}
+	conn, err := net.Dial(hdlr.Network, hdlr.Address)
+	if err != nil {
+		return err
+	}
+	hdlr.conn = conn
+	return nil
+}
+
+// frame formats msg as an RFC 5424 syslog message
+func (hdlr *SyslogHandler) frame(level int, msg string) string {
+	priority := hdlr.Facility*8 + syslogSeverity(level)
+	return fmt.Sprintf("<%d>1 %s %s %s - - - %s",
+		priority,
+		time.Now().Format(time.RFC3339),
+		hdlr.Hostname,
+		hdlr.Tag,
+		msg,
+	)
+}
+
+// Emit log record to the syslog daemon, connecting lazily if needed
+func (hdlr *SyslogHandler) Emit(record *LogRecord) {
+	msg, err := hdlr.Formatter.Format(record)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Format record failed, [%v]\n", err)
+	}
+
+	if err := hdlr.connect(); err != nil {
+		fmt.Fprintf(os.Stderr, "Connect to syslog server %s failed, [%v]\n", hdlr.Address, err)
+		return
+	}
+
+	if _, err := fmt.Fprintln(hdlr.conn, hdlr.frame(record.Level, msg)); err != nil {
+		hdlr.conn = nil
+	}
+}
+
+// Filter check if handler should filter the specified record
+func (hdlr *SyslogHandler) Filter(record *LogRecord) bool {
+	if record.Level < hdlr.Level {
+		return true
+	}
+	return false
+}
+
+// Handle the specified record, filter and emit it
+func (hdlr *SyslogHandler) Handle(record *LogRecord) {
+	filtered := hdlr.Filter(record)
+	if !filtered {
+		hdlr.mu.Lock()
+		defer hdlr.mu.Unlock()
+		hdlr.Emit(record)
+	}
+}
+
+// Close the connection to the syslog daemon, if not return error
+func (hdlr *SyslogHandler) Close() error {
+	if hdlr.conn == nil {
+		return nil
+	}
+	return hdlr.conn.Close()
+}
+
+func init() {
+	RegisterConstructor("SyslogHandler", func() ConfigLoader {
+		return NewSyslogHandler()
+	})
+}
@@ -0,0 +1,50 @@
+// Copyright 2016 Jim Zhang (jim.zoumo@gmail.com)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package logdog
+
+import (
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyslogSeverityMapsLevelToRFC5424Severity(t *testing.T) {
+	assert.Equal(t, 7, syslogSeverity(DEBUG))
+	assert.Equal(t, 6, syslogSeverity(INFO))
+	assert.Equal(t, 4, syslogSeverity(WARNING))
+	assert.Equal(t, 3, syslogSeverity(ERROR))
+	assert.Equal(t, 2, syslogSeverity(CRITICAL))
+}
+
+var rfc5424Pattern = regexp.MustCompile(`^<(\d+)>1 \S+ (\S+) (\S+) - - - (.*)$`)
+
+func TestSyslogHandlerFrameMatchesRFC5424(t *testing.T) {
+	hdlr := NewSyslogHandler()
+	hdlr.Facility = facilityLocal0
+	hdlr.Tag = "myapp"
+	hdlr.Hostname = "myhost"
+
+	frame := hdlr.frame(ERROR, "something went wrong")
+
+	matches := rfc5424Pattern.FindStringSubmatch(frame)
+	assert.NotNil(t, matches, "frame %q should match RFC 5424 syntax", frame)
+	assert.Equal(t, "myhost", matches[2])
+	assert.Equal(t, "myapp", matches[3])
+	assert.Equal(t, "something went wrong", matches[4])
+
+	priority := facilityLocal0*8 + syslogSeverity(ERROR)
+	assert.Equal(t, strconv.Itoa(priority), matches[1])
+}
@@ -0,0 +1,189 @@
+// Copyright 2016 Jim Zhang (jim.zoumo@gmail.com)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package logdog
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// globalV is the verbosity level used for callers whose file does not
+// match any SetVModule pattern.
+var globalV int32
+
+// vmoduleGeneration is bumped by SetV and SetVModule, invalidating every
+// cached per-file threshold so the next V() call on that file recomputes it.
+var vmoduleGeneration int64
+
+var (
+	vmoduleMu       sync.RWMutex
+	vmodulePatterns []vmoduleEntry
+)
+
+// vmoduleActive is 1 once SetVModule has configured at least one
+// pattern, 0 otherwise. V/VEnabled check this with a single atomic load
+// before paying for a runtime.Caller stack walk, since the overwhelming
+// majority of processes never call SetVModule and only ever gate on the
+// global level.
+var vmoduleActive int32
+
+type vmoduleEntry struct {
+	pattern string
+	level   int32
+}
+
+// verboseCache is the per-file memoized threshold; it is only ever
+// recomputed when vmoduleGeneration has moved on.
+type verboseCache struct {
+	generation int64
+	threshold  int32
+}
+
+var verboseCacheMap sync.Map // file string -> *verboseCache
+
+// SetV sets the global verbosity level used by logger.V(n) for files that
+// SetVModule does not otherwise cover.
+func SetV(level int) {
+	atomic.StoreInt32(&globalV, int32(level))
+	atomic.AddInt64(&vmoduleGeneration, 1)
+}
+
+// SetVModule configures per-file verbosity thresholds from a
+// comma-separated "pattern=level" list, for example
+// "handlers.go=2,stream_*=3". Patterns are matched against the base name
+// of the caller's file with filepath.Match, so "*" and "?" behave as
+// shell globs. An empty spec clears all per-file overrides.
+func SetVModule(spec string) error {
+	var entries []vmoduleEntry
+	if spec != "" {
+		for _, part := range strings.Split(spec, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			kv := strings.SplitN(part, "=", 2)
+			if len(kv) != 2 {
+				return fmt.Errorf("invalid vmodule entry: %s", part)
+			}
+			level, err := strconv.Atoi(kv[1])
+			if err != nil {
+				return fmt.Errorf("invalid vmodule level in %q: %v", part, err)
+			}
+			entries = append(entries, vmoduleEntry{pattern: kv[0], level: int32(level)})
+		}
+	}
+
+	vmoduleMu.Lock()
+	vmodulePatterns = entries
+	vmoduleMu.Unlock()
+
+	if len(entries) > 0 {
+		atomic.StoreInt32(&vmoduleActive, 1)
+	} else {
+		atomic.StoreInt32(&vmoduleActive, 0)
+	}
+	atomic.AddInt64(&vmoduleGeneration, 1)
+	return nil
+}
+
+// thresholdForFile returns the configured verbosity threshold for file,
+// preferring the first matching vmodule pattern over the global level.
+func thresholdForFile(file string) int32 {
+	base := filepath.Base(file)
+
+	vmoduleMu.RLock()
+	defer vmoduleMu.RUnlock()
+	for _, entry := range vmodulePatterns {
+		if ok, _ := filepath.Match(entry.pattern, base); ok {
+			return entry.level
+		}
+	}
+	return atomic.LoadInt32(&globalV)
+}
+
+// verbosityEnabled reports whether level n is enabled for file. On the
+// common path - no SetV/SetVModule call since the last check for this
+// file - this is a sync.Map lookup, a few atomic loads and a comparison,
+// with no allocation.
+func verbosityEnabled(file string, n int) bool {
+	gen := atomic.LoadInt64(&vmoduleGeneration)
+
+	if cached, ok := verboseCacheMap.Load(file); ok {
+		c := cached.(*verboseCache)
+		if atomic.LoadInt64(&c.generation) == gen {
+			return atomic.LoadInt32(&c.threshold) >= int32(n)
+		}
+	}
+
+	threshold := thresholdForFile(file)
+	verboseCacheMap.Store(file, &verboseCache{generation: gen, threshold: threshold})
+	return threshold >= int32(n)
+}
+
+// Verbose is returned by Logger.V and gates a log site at a given
+// verbosity level. Its methods are safe to call unconditionally: they are
+// no-ops when the level is not enabled.
+type Verbose struct {
+	logger  *Logger
+	enabled bool
+}
+
+// V reports whether verbosity level n is enabled for the calling file and
+// returns a Verbose that Info can be called on unconditionally. When no
+// SetVModule pattern is configured this never walks the stack: it is a
+// single atomic load of the global level, same as VEnabled.
+func (logger *Logger) V(n int) Verbose {
+	if atomic.LoadInt32(&vmoduleActive) == 0 {
+		return Verbose{logger: logger, enabled: atomic.LoadInt32(&globalV) >= int32(n)}
+	}
+
+	_, file, _, ok := runtime.Caller(1)
+	if !ok {
+		return Verbose{logger: logger, enabled: true}
+	}
+	return Verbose{logger: logger, enabled: verbosityEnabled(file, n)}
+}
+
+// VEnabled reports whether verbosity level n is enabled for the calling
+// file, for guarding expensive argument construction without allocating
+// a Verbose. Like V, it only pays for runtime.Caller once SetVModule has
+// configured at least one per-file pattern.
+func (logger *Logger) VEnabled(n int) bool {
+	if atomic.LoadInt32(&vmoduleActive) == 0 {
+		return atomic.LoadInt32(&globalV) >= int32(n)
+	}
+
+	_, file, _, ok := runtime.Caller(1)
+	if !ok {
+		return true
+	}
+	return verbosityEnabled(file, n)
+}
+
+// Enabled reports whether this Verbose will actually emit.
+func (v Verbose) Enabled() bool {
+	return v.enabled
+}
+
+// Info logs msg at INFO level if this Verbose's level is enabled.
+func (v Verbose) Info(msg string, fields ...Fields) {
+	if v.enabled {
+		v.logger.Info(msg, fields...)
+	}
+}
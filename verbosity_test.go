@@ -0,0 +1,51 @@
+// Copyright 2016 Jim Zhang (jim.zoumo@gmail.com)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package logdog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVFastPathWithoutVModule(t *testing.T) {
+	assert.NoError(t, SetVModule(""))
+	defer SetV(0)
+
+	SetV(2)
+	logger := NewLogger()
+	assert.True(t, logger.V(1).Enabled())
+	assert.True(t, logger.V(2).Enabled())
+	assert.False(t, logger.V(3).Enabled())
+}
+
+func TestVModuleOverridesGlobal(t *testing.T) {
+	SetV(0)
+	assert.NoError(t, SetVModule("verbosity_test.go=5"))
+	defer SetVModule("")
+
+	logger := NewLogger()
+	assert.True(t, logger.V(5).Enabled())
+	assert.False(t, logger.V(6).Enabled())
+}
+
+func BenchmarkVWithoutVModule(b *testing.B) {
+	assert.NoError(b, SetVModule(""))
+	SetV(1)
+	logger := NewLogger()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = logger.V(1).Enabled()
+	}
+}